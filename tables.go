@@ -0,0 +1,119 @@
+// Copyright (c) Microsoft Corporation
+// Licensed under the MIT License.
+
+package winmd
+
+// The structs below are consumed by cmd/genlayout (see its package doc)
+// to generate zlayout.go. They are the only fixture genlayout's
+// DocumentName/SequencePoints column kinds and @heap directive have to
+// exercise in this tree; the rest of the ECMA-335 table set (TypeDef,
+// MethodDef, and friends) that these PDB tables reference by @ref is
+// out of scope here and assumed to live alongside this file elsewhere.
+
+// DocumentName is a Blob heap index holding a compressed Portable PDB
+// document name, distinct from a plain BlobIndex so genlayout gives it
+// its own column kind in tableSchemas. Its width, decode, encode and
+// Row value are those of a plain blob index; the document-name payload
+// is not decoded.
+type DocumentName BlobIndex
+
+// SequencePoints is a Blob heap index holding a Portable PDB
+// sequence-points blob, distinct from a plain BlobIndex so genlayout
+// gives it its own column kind in tableSchemas. Its width, decode,
+// encode and Row value are those of a plain blob index; the
+// sequence-points payload is not decoded.
+type SequencePoints BlobIndex
+
+// Document is a row of the Portable PDB Document table (0x30). There is
+// one row per source document referenced by a MethodDebugInformation's
+// sequence points or by a CustomDebugInformation row.
+//
+// @table=0x30
+// @heap=#Pdb
+type Document struct {
+	Name          DocumentName
+	HashAlgorithm GUIDIndex
+	Hash          BlobIndex
+	Language      GUIDIndex
+}
+
+// MethodDebugInformation is a row of the Portable PDB
+// MethodDebugInformation table (0x31). There is one row per MethodDef
+// row, giving the method's containing document and sequence points.
+//
+// @table=0x31
+// @heap=#Pdb
+type MethodDebugInformation struct {
+	Document       Index // @ref=Document
+	SequencePoints SequencePoints
+}
+
+// LocalScope is a row of the Portable PDB LocalScope table (0x32),
+// describing the lexical scope of a run of local variables and
+// constants within a method body.
+//
+// @table=0x32
+// @heap=#Pdb
+type LocalScope struct {
+	Method       Index // @ref=MethodDef
+	ImportScope  Index // @ref=ImportScope
+	VariableList Index // @ref=LocalVariable
+	ConstantList Index // @ref=LocalConstant
+	StartOffset  uint32
+	Length       uint32
+}
+
+// LocalVariable is a row of the Portable PDB LocalVariable table
+// (0x33), one row per local variable belonging to a LocalScope.
+//
+// @table=0x33
+// @heap=#Pdb
+type LocalVariable struct {
+	Attributes uint16
+	Index      uint16
+	Name       String
+}
+
+// LocalConstant is a row of the Portable PDB LocalConstant table
+// (0x34), one row per local constant belonging to a LocalScope.
+//
+// @table=0x34
+// @heap=#Pdb
+type LocalConstant struct {
+	Name      String
+	Signature BlobIndex
+}
+
+// ImportScope is a row of the Portable PDB ImportScope table (0x35),
+// describing the imports (namespaces, aliases, ...) visible to a
+// LocalScope, optionally inheriting from a parent scope.
+//
+// @table=0x35
+// @heap=#Pdb
+type ImportScope struct {
+	Parent  Index // @ref=ImportScope
+	Imports BlobIndex
+}
+
+// StateMachineMethod is a row of the Portable PDB StateMachineMethod
+// table (0x36), mapping a compiler-generated async/iterator move-next
+// method back to the user-authored kickoff method.
+//
+// @table=0x36
+// @heap=#Pdb
+type StateMachineMethod struct {
+	MoveNextMethod Index // @ref=MethodDef
+	KickoffMethod  Index // @ref=MethodDef
+}
+
+// CustomDebugInformation is a row of the Portable PDB
+// CustomDebugInformation table (0x37), attaching an arbitrary,
+// GUID-tagged blob of debug data to any row in any table.
+//
+// @table=0x37
+// @heap=#Pdb
+type CustomDebugInformation struct {
+	Parent CodedIndex // @code=HasCustomDebugInformation
+	Kind   GUIDIndex
+	Value  BlobIndex
+}